@@ -0,0 +1,111 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupVersion is the API group and version used to register the AppRepository CRD.
+var GroupVersion = schema.GroupVersion{Group: "application.kubesphere.io", Version: "v1alpha1"}
+
+// SchemeBuilder collects the types this package exposes to the controller-runtime scheme.
+var SchemeBuilder = runtime.NewSchemeBuilder(func(s *runtime.Scheme) error {
+	s.AddKnownTypes(GroupVersion, &AppRepository{}, &AppRepositoryList{})
+	metav1.AddToGroupVersion(s, GroupVersion)
+	return nil
+})
+
+// AddToScheme registers the AppRepository types with the given scheme.
+var AddToScheme = SchemeBuilder.AddToScheme
+
+// AppRepositorySpec describes a Helm repository to reconcile into KubeSphere applications.
+type AppRepositorySpec struct {
+	// URL is the Helm index URL (http(s):// or oci://).
+	URL string `json:"url"`
+	// CategoryName labels imported applications for later lookup.
+	CategoryName string `json:"categoryName,omitempty"`
+	// Workspace is the KubeSphere workspace new applications are created in.
+	Workspace string `json:"workspace,omitempty"`
+	// VersionLimit caps how many versions of each chart are imported per sync.
+	VersionLimit int `json:"versionLimit,omitempty"`
+	// SyncInterval controls how often the repository is re-reconciled, e.g. "15m".
+	SyncInterval string `json:"syncInterval,omitempty"`
+	// AuthSecretRef names a Secret in the same namespace holding repo credentials.
+	AuthSecretRef string `json:"authSecretRef,omitempty"`
+}
+
+// AppRepositoryChartStatus reports the last reconcile outcome for a single chart.
+type AppRepositoryChartStatus struct {
+	Name         string      `json:"name"`
+	Succeeded    int         `json:"succeeded"`
+	Failed       int         `json:"failed"`
+	LastSyncTime metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// AppRepositoryStatus is the status subresource reported by the controller.
+type AppRepositoryStatus struct {
+	LastSyncTime metav1.Time                `json:"lastSyncTime,omitempty"`
+	Charts       []AppRepositoryChartStatus `json:"charts,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// AppRepository is the Schema for reconciling a Helm repository into KubeSphere applications.
+type AppRepository struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AppRepositorySpec   `json:"spec,omitempty"`
+	Status AppRepositoryStatus `json:"status,omitempty"`
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *AppRepository) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := new(AppRepository)
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status.LastSyncTime = in.Status.LastSyncTime
+	if in.Status.Charts != nil {
+		out.Status.Charts = make([]AppRepositoryChartStatus, len(in.Status.Charts))
+		copy(out.Status.Charts, in.Status.Charts)
+	}
+	return out
+}
+
+// +kubebuilder:object:root=true
+
+// AppRepositoryList contains a list of AppRepository.
+type AppRepositoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AppRepository `json:"items"`
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *AppRepositoryList) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := new(AppRepositoryList)
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]AppRepository, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyObject().(*AppRepository).DeepCopyInto(&out.Items[i])
+		}
+	}
+	return out
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *AppRepository) DeepCopyInto(out *AppRepository) {
+	*out = *in.DeepCopyObject().(*AppRepository)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// isNotFound reports whether err is a Kubernetes "not found" API error; nil is not.
+func isNotFound(err error) bool {
+	return err != nil && apierrors.IsNotFound(err)
+}
+
+var configMapGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+var (
+	checkpointFile      string
+	checkpointConfigMap string
+	checkpointNamespace string
+)
+
+// checkpointKey identifies a single chart version uploaded to a specific cluster.
+func checkpointKey(cluster, chart, version string) string {
+	return cluster + "@" + chart + "@" + version
+}
+
+// Checkpoint tracks which {cluster, chart, version} triples have already been
+// uploaded so a re-run can skip them instead of re-posting every chart from scratch.
+type Checkpoint struct {
+	mu   sync.Mutex
+	done map[string]bool
+
+	client    dynamic.Interface
+	file      string
+	configMap string
+	namespace string
+}
+
+// loadCheckpoint opens the configured checkpoint store (a local file, or a ConfigMap
+// fetched through client when running in-cluster) and preloads whatever progress it
+// already recorded. With neither --checkpoint-file nor --checkpoint-configmap set, it
+// starts empty and persistence is a no-op.
+func loadCheckpoint(client dynamic.Interface) (*Checkpoint, error) {
+	c := &Checkpoint{
+		done:      map[string]bool{},
+		client:    client,
+		file:      checkpointFile,
+		configMap: checkpointConfigMap,
+		namespace: checkpointNamespace,
+	}
+
+	var raw []byte
+	switch {
+	case c.configMap != "":
+		cm, err := c.client.Resource(configMapGVR).Namespace(c.namespace).Get(context.TODO(), c.configMap, metav1.GetOptions{})
+		if err != nil {
+			if !isNotFound(err) {
+				return nil, fmt.Errorf("failed to load checkpoint configmap %s/%s: %w", c.namespace, c.configMap, err)
+			}
+			return c, nil
+		}
+		data, _, _ := unstructured.NestedString(cm.Object, "data", "checkpoint")
+		raw = []byte(data)
+	case c.file != "":
+		data, err := os.ReadFile(c.file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return c, nil
+			}
+			return nil, fmt.Errorf("failed to read checkpoint file %s: %w", c.file, err)
+		}
+		raw = data
+	default:
+		return c, nil
+	}
+
+	if len(raw) == 0 {
+		return c, nil
+	}
+	var keys []string
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint data: %w", err)
+	}
+	for _, k := range keys {
+		c.done[k] = true
+	}
+	return c, nil
+}
+
+// IsDone reports whether cluster/chart/version was already uploaded in a previous run.
+func (c *Checkpoint) IsDone(cluster, chart, version string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done[checkpointKey(cluster, chart, version)]
+}
+
+// MarkDone records cluster/chart/version as uploaded and persists the checkpoint
+// immediately, so a crash mid-import loses at most the single in-flight upload. The
+// read-modify-persist sequence runs under c.mu in full (not just the map update), so
+// concurrent MarkDone calls -- from --concurrency workers or from multiple cluster
+// targets sharing this Checkpoint -- persist in a single total order instead of
+// racing to overwrite each other with a stale snapshot.
+func (c *Checkpoint) MarkDone(cluster, chart, version string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.done[checkpointKey(cluster, chart, version)] = true
+
+	if c.file == "" && c.configMap == "" {
+		return nil
+	}
+
+	keys := make([]string, 0, len(c.done))
+	for k := range c.done {
+		keys = append(keys, k)
+	}
+
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint data: %w", err)
+	}
+
+	if c.configMap != "" {
+		return c.saveConfigMap(data)
+	}
+	return os.WriteFile(c.file, data, 0o644)
+}
+
+func (c *Checkpoint) saveConfigMap(data []byte) error {
+	ctx := context.TODO()
+	cm, err := c.client.Resource(configMapGVR).Namespace(c.namespace).Get(ctx, c.configMap, metav1.GetOptions{})
+	if isNotFound(err) {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      c.configMap,
+				"namespace": c.namespace,
+			},
+			"data": map[string]interface{}{
+				"checkpoint": string(data),
+			},
+		}}
+		_, err = c.client.Resource(configMapGVR).Namespace(c.namespace).Create(ctx, obj, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get checkpoint configmap %s/%s: %w", c.namespace, c.configMap, err)
+	}
+
+	unstructured.SetNestedField(cm.Object, string(data), "data", "checkpoint")
+	_, err = c.client.Resource(configMapGVR).Namespace(c.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
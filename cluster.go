@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/phuslu/log"
+	"golang.org/x/sync/errgroup"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+var (
+	clustersConfig     string
+	clustersDir        string
+	workspaceMap       string
+	clusterParallelism int
+)
+
+// ClusterTarget is one KubeSphere host cluster to fan an import out to.
+type ClusterTarget struct {
+	Name          string `json:"name"`
+	Kubeconfig    string `json:"kubeconfig,omitempty"`
+	ServerURL     string `json:"server"`
+	Token         string `json:"token"`
+	Workspace     string `json:"workspace,omitempty"`
+	DynamicClient *dynamic.DynamicClient
+	RestyClient   *resty.Client
+}
+
+// init builds the dynamic and resty clients for this target from its own kubeconfig,
+// falling back to the ambient in-cluster/kubeconfig config when none is set. If
+// ServerURL/Token weren't set explicitly (e.g. a --clusters-dir entry), they're
+// derived from the kubeconfig's own cluster server and bearer token.
+func (t *ClusterTarget) init() error {
+	restConf, err := restConfigFor(t.Kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	if t.ServerURL == "" {
+		t.ServerURL = restConf.Host
+	}
+	if t.Token == "" {
+		t.Token = restConf.BearerToken
+	}
+	if t.ServerURL == "" || t.Token == "" {
+		return fmt.Errorf("cluster target %s has no server URL/token: set them in --clusters-config or point --clusters-dir at a kubeconfig with a bearer token", t.Name)
+	}
+
+	t.DynamicClient, err = dynamic.NewForConfig(restConf)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	t.RestyClient = resty.New().SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", t.Token)).
+		SetTimeout(time.Second * 5)
+
+	return nil
+}
+
+func restConfigFor(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig == "" {
+		return config.GetConfig()
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+// loadClusterTargets builds the cluster fan-out list from --clusters-config or
+// --clusters-dir, or falls back to a single implicit target built from
+// --server/--token/the ambient kubeconfig so single-cluster invocations are
+// unchanged. --clusters-config entries carry their own server/token explicitly;
+// --clusters-dir entries have none, so each one's server URL and bearer token are
+// derived from its own kubeconfig by ClusterTarget.init.
+func loadClusterTargets() ([]*ClusterTarget, error) {
+	var targets []*ClusterTarget
+
+	switch {
+	case clustersConfig != "":
+		data, err := os.ReadFile(clustersConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --clusters-config: %w", err)
+		}
+		if err := json.Unmarshal(data, &targets); err != nil {
+			return nil, fmt.Errorf("failed to parse --clusters-config: %w", err)
+		}
+	case clustersDir != "":
+		entries, err := os.ReadDir(clustersDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --clusters-dir: %w", err)
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+			targets = append(targets, &ClusterTarget{
+				Name:       name,
+				Kubeconfig: filepath.Join(clustersDir, e.Name()),
+			})
+		}
+	default:
+		targets = append(targets, &ClusterTarget{Name: "default", ServerURL: serverURL, Token: token})
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no cluster targets found")
+	}
+
+	workspaces := parseWorkspaceMap(workspaceMap)
+	for _, t := range targets {
+		if ws, ok := workspaces[t.Name]; ok {
+			t.Workspace = ws
+		}
+		if err := t.init(); err != nil {
+			return nil, fmt.Errorf("failed to initialize cluster target %s: %w", t.Name, err)
+		}
+	}
+
+	return targets, nil
+}
+
+// parseWorkspaceMap parses a "cluster=workspace,cluster2=workspace2" --workspace-map
+// flag into a lookup table.
+func parseWorkspaceMap(spec string) map[string]string {
+	result := map[string]string{}
+	if spec == "" {
+		return result
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		result[kv[0]] = kv[1]
+	}
+	return result
+}
+
+// importToCluster runs one full import (chart upload + the four status/label passes)
+// against a single cluster target and returns a one-line summary of the outcome.
+// checkpoint is shared across every concurrently running cluster target in this run.
+func importToCluster(target *ClusterTarget, auth *RepoAuth, controlClient dynamic.Interface, checkpoint *Checkpoint, spec ImportSpec) string {
+	log.Info().Msgf("[%s] Starting to upload to %s", target.Name, target.ServerURL)
+
+	if _, err := uploadChart(target, auth, controlClient, checkpoint, spec); err != nil {
+		return fmt.Sprintf("[%s] FAILED to upload chart: %v", target.Name, err)
+	}
+
+	listOptions := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("application.kubesphere.io/app-category-name=%s", spec.Mark),
+	}
+
+	if err := updateAppStatus(target, listOptions); err != nil {
+		return fmt.Sprintf("[%s] FAILED at [1/4] update app status: %v", target.Name, err)
+	}
+
+	store := map[string]string{"application.kubesphere.io/app-store": "true"}
+	if err := updateAppLabel(target, listOptions, labelMutator(store)); err != nil {
+		return fmt.Sprintf("[%s] FAILED at [2/4] update app label: %v", target.Name, err)
+	}
+
+	if err := updateVersionStatus(target, listOptions); err != nil {
+		return fmt.Sprintf("[%s] FAILED at [3/4] update version status: %v", target.Name, err)
+	}
+
+	categoryName := map[string]string{"application.kubesphere.io/app-category-name": "kubesphere-app-uncategorized"}
+	if err := updateAppLabel(target, listOptions, labelMutator(categoryName)); err != nil {
+		return fmt.Sprintf("[%s] FAILED at [4/4] update app category label: %v", target.Name, err)
+	}
+
+	return fmt.Sprintf("[%s] OK: import completed", target.Name)
+}
+
+// runMultiCluster loads the configured cluster targets and fans the import out across
+// them, capped at clusterParallelism concurrent clusters, aggregating a final report.
+func runMultiCluster(spec ImportSpec) error {
+	targets, err := loadClusterTargets()
+	if err != nil {
+		return err
+	}
+
+	controlClient := targets[0].DynamicClient
+	auth, err := loadRepoAuth(context.TODO(), controlClient, repoAuthSecret, repoAuthNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to load repo auth: %w", err)
+	}
+	defer auth.Cleanup()
+
+	checkpoint, err := loadCheckpoint(controlClient)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	g := new(errgroup.Group)
+	g.SetLimit(maxInt(clusterParallelism, 1))
+
+	reports := make([]string, len(targets))
+	for i, target := range targets {
+		i, target := i, target
+		g.Go(func() error {
+			reports[i] = importToCluster(target, auth, controlClient, checkpoint, spec)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	for _, r := range reports {
+		log.Info().Msg(r)
+	}
+
+	return nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
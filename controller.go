@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/phuslu/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// AppRepositoryReconciler reconciles an AppRepository by importing its Helm index
+// into KubeSphere applications and recording per-chart status. Target is the single
+// host cluster the controller runs against; unlike the --one-shot multi-cluster
+// fan-out, a controller instance only ever reconciles its own cluster.
+type AppRepositoryReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Target *ClusterTarget
+}
+
+// SetupWithManager wires the reconciler into mgr, watching AppRepository objects.
+func (r *AppRepositoryReconciler) SetupWithManager(mgr manager.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&AppRepository{}).
+		Complete(r)
+}
+
+// Reconcile downloads the repository's Helm index, uploads chart versions not yet
+// recorded in the checkpoint store (--checkpoint-file/--checkpoint-configmap), and
+// updates the AppRepository status subresource. Existing Application resources are
+// not consulted, so without a checkpoint store every version within the limit is
+// uploaded again on each sync.
+func (r *AppRepositoryReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	var repository AppRepository
+	if err := r.Get(ctx, req.NamespacedName, &repository); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		log.Error().Msgf("Failed to get AppRepository %s: %v", req.NamespacedName, err)
+		return reconcile.Result{}, err
+	}
+
+	spec := ImportSpec{
+		RepoURL: repository.Spec.URL,
+		Mark:    repository.Spec.CategoryName,
+		Limit:   repository.Spec.VersionLimit,
+	}
+	if spec.Mark == "" {
+		spec.Mark = "openpitrix-import"
+	}
+	if spec.Limit <= 0 {
+		spec.Limit = 1
+	}
+	// Per-reconcile copy, so the workspace of one AppRepository is never written
+	// onto the Target shared by every Reconcile.
+	target := *r.Target
+	target.Workspace = repository.Spec.Workspace
+
+	authSecret, authNamespace := repository.Spec.AuthSecretRef, repository.Namespace
+	if authSecret == "" {
+		authSecret, authNamespace = repoAuthSecret, repoAuthNamespace
+	}
+	auth, err := loadRepoAuth(ctx, target.DynamicClient, authSecret, authNamespace)
+	if err != nil {
+		log.Error().Msgf("AppRepository %s: failed to load repo auth: %v", req.NamespacedName, err)
+		return reconcile.Result{}, err
+	}
+	defer auth.Cleanup()
+
+	checkpoint, err := loadCheckpoint(target.DynamicClient)
+	if err != nil {
+		log.Error().Msgf("AppRepository %s: failed to load checkpoint: %v", req.NamespacedName, err)
+		return reconcile.Result{}, err
+	}
+
+	chartResults, uploadErr := uploadChart(&target, auth, target.DynamicClient, checkpoint, spec)
+	if uploadErr != nil {
+		log.Error().Msgf("AppRepository %s: failed to upload chart: %v", req.NamespacedName, uploadErr)
+	}
+
+	listOptions := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("application.kubesphere.io/app-category-name=%s", spec.Mark),
+	}
+	if err := updateAppStatus(&target, listOptions); err != nil {
+		log.Error().Msgf("AppRepository %s: failed to update app status: %v", req.NamespacedName, err)
+	}
+
+	repository.Status.LastSyncTime = metav1.NewTime(time.Now().UTC())
+	repository.Status.Charts = chartResults
+	if err := r.Status().Update(ctx, &repository); err != nil {
+		log.Error().Msgf("AppRepository %s: failed to update status: %v", req.NamespacedName, err)
+		return reconcile.Result{}, err
+	}
+
+	interval := 15 * time.Minute
+	if repository.Spec.SyncInterval != "" {
+		if parsed, err := time.ParseDuration(repository.Spec.SyncInterval); err == nil {
+			interval = parsed
+		} else {
+			log.Warn().Msgf("AppRepository %s: invalid syncInterval %q, using default: %v", req.NamespacedName, repository.Spec.SyncInterval, err)
+		}
+	}
+
+	return reconcile.Result{RequeueAfter: interval}, nil
+}
+
+// runController starts a controller-runtime manager with leader election enabled and
+// blocks until it exits, reconciling AppRepository objects until the process is stopped.
+func runController(restyClient *resty.Client) error {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		return fmt.Errorf("failed to register AppRepository scheme: %w", err)
+	}
+
+	mgrConfig := ctrl.GetConfigOrDie()
+	mgr, err := ctrl.NewManager(mgrConfig, ctrl.Options{
+		Scheme:                  scheme,
+		LeaderElection:          true,
+		LeaderElectionID:        "kubesphere-app-tool-leader",
+		LeaderElectionNamespace: "kubesphere-system",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(mgrConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	reconciler := &AppRepositoryReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Target: &ClusterTarget{
+			Name:          "controller",
+			ServerURL:     serverURL,
+			Token:         token,
+			DynamicClient: dynamicClient,
+			RestyClient:   restyClient,
+		},
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("failed to set up AppRepository controller: %w", err)
+	}
+
+	log.Info().Msg("Starting AppRepository controller manager")
+	return mgr.Start(ctrl.SetupSignalHandler())
+}
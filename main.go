@@ -5,11 +5,13 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/phuslu/log"
 	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/repo"
@@ -17,7 +19,6 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
 type AppRequest struct {
@@ -28,6 +29,17 @@ type AppRequest struct {
 	AppType      string `json:"appType"`
 }
 
+// ImportSpec is the set of per-run Helm import parameters -- the repo URL, the
+// category label new applications are tagged with, and the per-chart version cap.
+// It's built once per run/reconcile and threaded explicitly through the upload path
+// instead of being mutated as package globals, so bumping controller-runtime's
+// MaxConcurrentReconciles above 1 can't race two Reconciles against shared state.
+type ImportSpec struct {
+	RepoURL string
+	Mark    string
+	Limit   int
+}
+
 var (
 	versionGVR = schema.GroupVersionResource{
 		Group:    "application.kubesphere.io",
@@ -39,12 +51,12 @@ var (
 		Version:  "v2",
 		Resource: "applications",
 	}
-	mark          = "openpitrix-import"
-	dynamicClient *dynamic.DynamicClient
-	serverURL     string
-	token         string
-	repoURL       string
-	limit         int // limit version for each chart
+	mark      = "openpitrix-import"
+	serverURL string
+	token     string
+	repoURL   string
+	limit     int  // limit version for each chart
+	oneShot   bool // preserve the legacy fire-and-forget CLI behavior instead of running the controller
 )
 
 func init() {
@@ -60,14 +72,14 @@ func init() {
 }
 
 func main() {
-	resty_client := resty.New().SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("Bearer %s", token)).
-		SetTimeout(time.Second * 5)
-
 	var rootCmd = &cobra.Command{
 		Use:   "app-tool",
 		Short: "A CLI tool to manage applications",
 		Run: func(cmd *cobra.Command, args []string) {
+			if oneShot && repoURL == "" {
+				log.Fatal().Msg("--repo is required when --one-shot is set")
+			}
+
 			if token == "" {
 				log.Info().Msg("Using token from /var/run/secrets/kubesphere.io/serviceaccount/token")
 				dst := "/var/run/secrets/kubesphere.io/serviceaccount/token"
@@ -77,17 +89,47 @@ func main() {
 				}
 				token = string(data)
 			}
-			run(resty_client)
+
+			// Built here, after token is fully resolved (flags parsed and the
+			// service-account fallback above applied), so its Authorization header
+			// carries the real token instead of whatever token held at package init.
+			restyClient := resty.New().SetHeader("Content-Type", "application/json").
+				SetHeader("Authorization", fmt.Sprintf("Bearer %s", token)).
+				SetTimeout(time.Second * 5)
+
+			if oneShot {
+				run(restyClient, ImportSpec{RepoURL: repoURL, Mark: mark, Limit: limit})
+				return
+			}
+
+			if err := runController(restyClient); err != nil {
+				log.Fatal().Msgf("Controller manager exited: %v", err)
+			}
 		},
 	}
 
 	rootCmd.Flags().StringVar(&serverURL, "server", "", "Kubesphere Server URL (required)")
-	rootCmd.Flags().StringVar(&repoURL, "repo", "", "Helm index URL (required)")
+	rootCmd.Flags().StringVar(&repoURL, "repo", "", "Helm index URL (required when --one-shot is set)")
 	rootCmd.Flags().StringVar(&token, "token", "", "token (required)")
 	rootCmd.Flags().IntVar(&limit, "limit", 1, "limit (option)")
+	rootCmd.Flags().BoolVar(&oneShot, "one-shot", false, "run a single import and exit instead of starting the AppRepository controller")
+	rootCmd.Flags().StringVar(&repoAuthSecret, "repo-auth-secret", "", "name of a Secret holding repo credentials (basic auth, bearer token, or client cert/CA)")
+	rootCmd.Flags().StringVar(&repoAuthNamespace, "repo-auth-secret-namespace", "default", "namespace of --repo-auth-secret")
+	rootCmd.Flags().IntVar(&concurrency, "concurrency", 1, "number of chart versions to upload in parallel")
+	rootCmd.Flags().Float64Var(&rateLimit, "rate-limit", 5, "max requests per second against the Kubesphere API")
+	rootCmd.Flags().BoolVar(&failFast, "fail-fast", false, "abort the import on the first failed chart upload instead of continuing")
+	rootCmd.Flags().StringVar(&checkpointFile, "checkpoint-file", "", "path to a file recording uploaded {cluster, chart, version} entries so a re-run can skip them")
+	rootCmd.Flags().StringVar(&checkpointConfigMap, "checkpoint-configmap", "", "name of a ConfigMap to use as the checkpoint store instead of --checkpoint-file (for in-cluster runs)")
+	rootCmd.Flags().StringVar(&checkpointNamespace, "checkpoint-namespace", "default", "namespace of --checkpoint-configmap")
+	rootCmd.Flags().BoolVar(&verifyCharts, "verify", false, "verify chart provenance before upload, refusing unsigned or invalid charts")
+	rootCmd.Flags().StringVar(&keyringPath, "keyring", "", "path to the (binary, non-armored) OpenPGP public keyring used with --verify")
+	rootCmd.Flags().StringVar(&keyringSecret, "keyring-secret", "", "name of a Secret (in --repo-auth-secret-namespace) holding the (binary, non-armored) keyring under its \"keyring\" key, used with --verify in-cluster")
+	rootCmd.Flags().StringVar(&clustersConfig, "clusters-config", "", "path to a JSON file listing []ClusterTarget to fan --one-shot imports out to")
+	rootCmd.Flags().StringVar(&clustersDir, "clusters-dir", "", "path to a directory of kubeconfigs, one cluster target per file, as an alternative to --clusters-config")
+	rootCmd.Flags().StringVar(&workspaceMap, "workspace-map", "", "comma-separated cluster=workspace pairs overriding the workspace new applications are created in per cluster target")
+	rootCmd.Flags().IntVar(&clusterParallelism, "clusters-parallelism", 2, "max number of cluster targets to import into concurrently")
 
 	rootCmd.MarkFlagRequired("server")
-	rootCmd.MarkFlagRequired("repo")
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -95,67 +137,42 @@ func main() {
 	}
 }
 
-func run(resty_client *resty.Client) {
-	log.Info().Msgf("Starting to upload to %s ", serverURL)
-
-	err := initDynamicClient()
-	if err != nil {
-		log.Fatal().Msgf("Failed to initialize dynamic client: %v", err)
-	}
-
-	err = uploadChart(resty_client)
-	if err != nil {
-		log.Fatal().Msgf("Failed to upload chart: %v", err)
-	}
-
-	listOptions := metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("application.kubesphere.io/app-category-name=%s", mark),
-	}
-
-	err = updateAppStatus(listOptions)
-	if err != nil {
-		log.Fatal().Msgf("[1/4] Failed to update app status: %v", err)
-	}
-	log.Info().Msgf("[1/4] updateAppStatus completed successfully")
-
-	store := map[string]string{"application.kubesphere.io/app-store": "true"}
-	err = updateAppLabel(listOptions, store)
-	if err != nil {
-		log.Fatal().Msgf("[2/4] Failed to update app label: %v", err)
-	}
-	log.Info().Msgf("[2/4] updateAppLabel store completed successfully")
-
-	err = updateVersionStatus(listOptions)
-	if err != nil {
-		log.Fatal().Msgf("[3/4] Failed to update version status: %v", err)
-	}
-	log.Info().Msgf("[3/4] updateVersionStatus completed successfully")
-
-	categoryName := map[string]string{"application.kubesphere.io/app-category-name": "kubesphere-app-uncategorized"}
-	err = updateAppLabel(listOptions, categoryName)
-	if err != nil {
-		log.Fatal().Msgf("[4/4] Failed to update app category label: %v", err)
+// run fans the import out across every configured cluster target (a single implicit
+// target by default; see --clusters-config/--clusters-dir).
+func run(_ *resty.Client, spec ImportSpec) {
+	if err := runMultiCluster(spec); err != nil {
+		log.Fatal().Msgf("Import failed: %v", err)
 	}
-	log.Info().Msgf("[4/4] updateAppLabel categoryName completed successfully")
 }
 
-func initDynamicClient() (err error) {
-	conf := config.GetConfigOrDie()
-	dynamicClient, err = dynamic.NewForConfig(conf)
-	if err != nil {
-		log.Error().Msgf("Failed to create dynamic client: %v", err)
-		return err
+// uploadChart downloads the Helm repository named by spec.RepoURL and uploads up to
+// spec.Limit versions of each chart to target, returning a per-chart success/failure
+// tally. It dispatches to the OCI or plain-index path depending on the scheme of
+// spec.RepoURL. checkpoint is shared across every cluster target in the run (see
+// loadClusterTargets callers), not reloaded per target, so concurrent cluster fan-out
+// persists through a single Checkpoint instance instead of racing independent copies
+// against the same backing file/ConfigMap.
+func uploadChart(target *ClusterTarget, auth *RepoAuth, controlClient dynamic.Interface, checkpoint *Checkpoint, spec ImportSpec) ([]AppRepositoryChartStatus, error) {
+	if strings.HasPrefix(spec.RepoURL, "oci://") {
+		return uploadOCIChart(target, auth, controlClient, checkpoint, spec)
 	}
-	log.Info().Msgf("Dynamic client initialized successfully")
-	return nil
+	return uploadIndexChart(target, auth, controlClient, checkpoint, spec)
 }
 
-func uploadChart(resty_client *resty.Client) error {
-	entry := &repo.Entry{
-		URL: repoURL,
+func uploadIndexChart(target *ClusterTarget, auth *RepoAuth, controlClient dynamic.Interface, checkpoint *Checkpoint, spec ImportSpec) ([]AppRepositoryChartStatus, error) {
+	if err := applyRepoAuthToClient(target.RestyClient, auth); err != nil {
+		return nil, err
 	}
 
-	chartRepo, err := repo.NewChartRepository(entry, getter.All(&cli.EnvSettings{}))
+	settings := cli.New()
+	// Start from any matching entry in the user's Helm repositories.yaml (honoring
+	// HELM_REPOSITORY_CONFIG) so repos already configured via
+	// `helm repo add` are usable without duplicating credentials on the command
+	// line; --repo-auth-secret credentials, if set, take precedence over it below.
+	entry := repoEntryFromHelmConfig(settings, spec.RepoURL)
+	applyRepoAuthToEntry(entry, auth)
+
+	chartRepo, err := repo.NewChartRepository(entry, getter.All(settings))
 	if err != nil {
 		log.Fatal().Msgf("failed to create chart repo: %v", err)
 	}
@@ -170,97 +187,181 @@ func uploadChart(resty_client *resty.Client) error {
 		log.Fatal().Msgf("failed to load index file: %v", err)
 	}
 
-	for _, entries := range indexData.Entries {
-		appID := ""
-		success := 0
+	var keyringPath string
+	if verifyCharts {
+		kr, err := resolveKeyring(controlClient)
+		if err != nil {
+			return nil, err
+		}
+		defer kr.Cleanup()
+		keyringPath = kr.path
+	}
+
+	jobs := buildChartVersionJobs(indexData, spec.Limit)
+
+	limiter := rate.NewLimiter(rate.Limit(rateLimit), 1)
+	jobResults := runWorkerPool(target.Name, jobs, checkpoint, limiter, func(job chartVersionJob) error {
+		return uploadChartVersion(target, auth, controlClient, keyringPath, spec.Mark, job)
+	})
+
+	return summarizeChartResults(jobResults), nil
+}
+
+// buildChartVersionJobs flattens the index into one job per chart version, honoring
+// the per-chart limit cap and skipping a chart's remaining versions once a
+// deprecated one is reached, same as the original serial loop did.
+func buildChartVersionJobs(indexData *repo.IndexFile, limit int) []chartVersionJob {
+	var jobs []chartVersionJob
 
+	for chartName, entries := range indexData.Entries {
+		taken := 0
 		for _, entry := range entries {
 			if entry.Deprecated {
 				log.Warn().Msgf("App %s is deprecated, skip", entry.Name)
 				break
 			}
-
-			// download data
-			req := resty_client.R()
-			resp, err := req.Get(entry.URLs[0])
-			if err != nil {
-				log.Error().Msgf("Failed to fetch chart %v, %v", entry.Name, err)
-				continue
+			if taken >= limit {
+				break
 			}
+			jobs = append(jobs, chartVersionJob{Chart: chartName, Version: entry.Version, URL: entry.URLs[0]})
+			taken++
+		}
+	}
 
-			if resp.IsError() {
-				log.Error().Msgf("Failed to fetch chart %v, status code: %d", entry.Name, resp.StatusCode())
-				continue
-			}
+	return jobs
+}
 
-			// upload data
-			var url string
-			if appID == "" {
-				url = fmt.Sprintf("%s/kapis/application.kubesphere.io/v2/apps", serverURL)
-			} else {
-				url = fmt.Sprintf("%s/kapis/application.kubesphere.io/v2/apps/%s/versions", serverURL, appID)
-			}
+// uploadChartVersion downloads a single chart tarball and posts it to target.
+func uploadChartVersion(target *ClusterTarget, auth *RepoAuth, controlClient dynamic.Interface, keyringPath, mark string, job chartVersionJob) error {
+	req := target.RestyClient.R()
+	applyRepoAuthToRequest(req, auth)
+	resp, err := req.Get(job.URL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch chart %s:%s: %w", job.Chart, job.Version, err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("failed to fetch chart %s:%s, status code: %d", job.Chart, job.Version, resp.StatusCode())
+	}
+	chartData := resp.Body()
 
-			var response struct {
-				AppName string `json:"appName"`
-			}
-			req = resty_client.R().SetBody(AppRequest{
-				RepoName:     "upload",
-				Package:      base64.StdEncoding.EncodeToString(resp.Body()),
-				CategoryName: mark,
-				Workspace:    "",
-				AppType:      "helm",
-			}).SetResult(&response)
-
-			resp, err = req.Post(url)
-			if err != nil {
-				log.Error().Msgf("Failed to post app version %s:%s %v", entry.Name, entry.Version, err)
-				continue
-			}
+	fingerprint, digest, err := verifyChartProvenance(keyringPath, func() ([]byte, error) {
+		provReq := target.RestyClient.R()
+		applyRepoAuthToRequest(provReq, auth)
+		provResp, err := provReq.Get(job.URL + ".prov")
+		if err != nil {
+			return nil, err
+		}
+		if provResp.IsError() {
+			return nil, fmt.Errorf("status code %d", provResp.StatusCode())
+		}
+		return provResp.Body(), nil
+	}, chartData, job.Chart, job.Version)
+	if err != nil {
+		return err
+	}
 
-			if resp.IsError() {
-				log.Error().Msgf("failed to post app, status code: %d", resp.StatusCode())
-				continue
-			}
+	url := fmt.Sprintf("%s/kapis/application.kubesphere.io/v2/apps", target.ServerURL)
+	var response struct {
+		AppName string `json:"appName"`
+	}
+	req = target.RestyClient.R().SetBody(AppRequest{
+		RepoName:     "upload",
+		Package:      base64.StdEncoding.EncodeToString(chartData),
+		CategoryName: mark,
+		Workspace:    target.Workspace,
+		AppType:      "helm",
+	}).SetResult(&response)
+
+	resp, err = req.Post(url)
+	if err != nil {
+		return fmt.Errorf("failed to post app version %s:%s: %w", job.Chart, job.Version, err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("failed to post app %s:%s, status code: %d", job.Chart, job.Version, resp.StatusCode())
+	}
 
-			log.Info().Msgf("App %s:%s posted successfully", entry.Name, entry.Version)
-			success++
-			if success >= limit {
-				break
-			}
+	log.Info().Msgf("[%s] App %s:%s posted successfully", target.Name, job.Chart, job.Version)
 
-			time.Sleep(200 * time.Millisecond)
+	if verifyCharts {
+		ann := map[string]string{
+			"application.kubesphere.io/provenance-fingerprint": fingerprint,
+			"application.kubesphere.io/provenance-digest":      digest,
+		}
+		if err := annotateApp(target.DynamicClient, response.AppName, ann); err != nil {
+			log.Error().Msgf("[%s] Failed to annotate app %s with provenance info: %v", target.Name, response.AppName, err)
 		}
 	}
 
 	return nil
 }
 
-func updateVersionStatus(listOptions metav1.ListOptions) error {
-	list, err := dynamicClient.Resource(appGVR).List(context.TODO(), listOptions)
+// summarizeChartResults aggregates per-job results into the per-chart tally reported
+// on AppRepository status, logging a summary line for the whole run.
+func summarizeChartResults(jobResults []chartVersionResult) []AppRepositoryChartStatus {
+	byChart := map[string]*AppRepositoryChartStatus{}
+	var order []string
+	var uploaded, skipped, failed int
+
+	for _, r := range jobResults {
+		status, ok := byChart[r.Chart]
+		if !ok {
+			status = &AppRepositoryChartStatus{Name: r.Chart}
+			byChart[r.Chart] = status
+			order = append(order, r.Chart)
+		}
+
+		switch {
+		case r.Skipped:
+			skipped++
+		case r.Err != nil:
+			status.Failed++
+			failed++
+			log.Error().Msgf("%v", r.Err)
+		default:
+			status.Succeeded++
+			uploaded++
+		}
+	}
+
+	results := make([]AppRepositoryChartStatus, 0, len(order))
+	now := metav1.NewTime(time.Now().UTC())
+	for _, name := range order {
+		status := byChart[name]
+		status.LastSyncTime = now
+		results = append(results, *status)
+	}
+
+	log.Info().Msgf("Import summary: %d uploaded, %d skipped (checkpointed), %d failed", uploaded, skipped, failed)
+	return results
+}
+
+func updateVersionStatus(target *ClusterTarget, listOptions metav1.ListOptions) error {
+	list, err := target.DynamicClient.Resource(appGVR).List(context.TODO(), listOptions)
 	if err != nil {
 		log.Error().Msgf("Failed to list apps: %v", err)
 		return err
 	}
 
+	mutate := func(obj *unstructured.Unstructured) error {
+		currentTime := time.Now().UTC().Format(time.RFC3339)
+		unstructured.SetNestedField(obj.Object, currentTime, "status", "updated")
+		unstructured.SetNestedField(obj.Object, "admin", "status", "userName")
+		unstructured.SetNestedField(obj.Object, "active", "status", "state")
+		return nil
+	}
+
 	for _, item := range list.Items {
 		options := metav1.ListOptions{
 			LabelSelector: fmt.Sprintf("application.kubesphere.io/app-id=%s", item.GetName()),
 		}
-		versionList, err := dynamicClient.Resource(versionGVR).List(context.TODO(), options)
+		versionList, err := target.DynamicClient.Resource(versionGVR).List(context.TODO(), options)
 		if err != nil {
 			log.Error().Msgf("Failed to list versions for app %s: %v", item.GetName(), err)
 			return err
 		}
 
 		for _, versionItem := range versionList.Items {
-			currentTime := time.Now().UTC().Format(time.RFC3339)
-			unstructured.SetNestedField(versionItem.Object, currentTime, "status", "updated")
-			unstructured.SetNestedField(versionItem.Object, "admin", "status", "userName")
-			unstructured.SetNestedField(versionItem.Object, "active", "status", "state")
-
-			_, err := dynamicClient.Resource(versionGVR).UpdateStatus(context.TODO(), &versionItem, metav1.UpdateOptions{})
-			if err != nil {
+			if err := retryUpdate(target.DynamicClient, versionGVR, versionItem.GetName(), mutate, true); err != nil {
 				log.Error().Msgf("Failed to update version status for app %s: %v", item.GetName(), err)
 				return err
 			}
@@ -270,22 +371,18 @@ func updateVersionStatus(listOptions metav1.ListOptions) error {
 	return nil
 }
 
-func updateAppLabel(listOptions metav1.ListOptions, label map[string]string) error {
-	list, err := dynamicClient.Resource(appGVR).List(context.TODO(), listOptions)
+// updateAppLabel re-fetches and re-applies mutate against every app matched by
+// listOptions, retrying on conflict. mutate should only touch the labels it cares
+// about so that it stays idempotent across retries.
+func updateAppLabel(target *ClusterTarget, listOptions metav1.ListOptions, mutate mutateFunc) error {
+	list, err := target.DynamicClient.Resource(appGVR).List(context.TODO(), listOptions)
 	if err != nil {
 		log.Error().Msgf("Failed to list apps: %v", err)
 		return err
 	}
 
 	for _, item := range list.Items {
-		labels := item.GetLabels()
-		for k, v := range label {
-			labels[k] = v
-		}
-
-		item.SetLabels(labels)
-		_, err = dynamicClient.Resource(appGVR).Update(context.TODO(), &item, metav1.UpdateOptions{})
-		if err != nil {
+		if err := retryUpdate(target.DynamicClient, appGVR, item.GetName(), mutate, false); err != nil {
 			log.Error().Msgf("Failed to update labels for app %s: %v", item.GetName(), err)
 			return err
 		}
@@ -294,20 +391,37 @@ func updateAppLabel(listOptions metav1.ListOptions, label map[string]string) err
 	return nil
 }
 
-func updateAppStatus(listOptions metav1.ListOptions) error {
-	list, err := dynamicClient.Resource(appGVR).List(context.TODO(), listOptions)
+// labelMutator returns a mutateFunc that merges label into the object's existing labels.
+func labelMutator(label map[string]string) mutateFunc {
+	return func(obj *unstructured.Unstructured) error {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for k, v := range label {
+			labels[k] = v
+		}
+		obj.SetLabels(labels)
+		return nil
+	}
+}
+
+func updateAppStatus(target *ClusterTarget, listOptions metav1.ListOptions) error {
+	list, err := target.DynamicClient.Resource(appGVR).List(context.TODO(), listOptions)
 	if err != nil {
 		log.Error().Msgf("Failed to list apps: %v", err)
 		return err
 	}
 
-	for _, item := range list.Items {
+	mutate := func(obj *unstructured.Unstructured) error {
 		currentTime := time.Now().UTC().Format(time.RFC3339)
-		unstructured.SetNestedField(item.Object, "active", "status", "state")
-		unstructured.SetNestedField(item.Object, currentTime, "status", "updateTime")
+		unstructured.SetNestedField(obj.Object, "active", "status", "state")
+		unstructured.SetNestedField(obj.Object, currentTime, "status", "updateTime")
+		return nil
+	}
 
-		_, err := dynamicClient.Resource(appGVR).UpdateStatus(context.TODO(), &item, metav1.UpdateOptions{})
-		if err != nil {
+	for _, item := range list.Items {
+		if err := retryUpdate(target.DynamicClient, appGVR, item.GetName(), mutate, true); err != nil {
 			log.Error().Msgf("Failed to update status for app %s: %v", item.GetName(), err)
 			return err
 		}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func chartVersion(name, version string, deprecated bool) *repo.ChartVersion {
+	return &repo.ChartVersion{
+		Metadata: &chart.Metadata{Name: name, Version: version, Deprecated: deprecated},
+		URLs:     []string{name + "-" + version + ".tgz"},
+	}
+}
+
+func TestBuildChartVersionJobsHonorsLimit(t *testing.T) {
+	indexData := &repo.IndexFile{Entries: map[string]repo.ChartVersions{
+		"demo": {
+			chartVersion("demo", "3.0.0", false),
+			chartVersion("demo", "2.0.0", false),
+			chartVersion("demo", "1.0.0", false),
+		},
+	}}
+
+	jobs := buildChartVersionJobs(indexData, 2)
+	if len(jobs) != 2 {
+		t.Fatalf("expected --limit=2 to cap the job count at 2, got %d: %+v", len(jobs), jobs)
+	}
+
+	var versions []string
+	for _, j := range jobs {
+		versions = append(versions, j.Version)
+	}
+	sort.Strings(versions)
+	if !reflect.DeepEqual(versions, []string{"2.0.0", "3.0.0"}) {
+		t.Fatalf("expected the first 2 versions to be taken, got %v", versions)
+	}
+}
+
+func TestBuildChartVersionJobsStopsAtDeprecated(t *testing.T) {
+	indexData := &repo.IndexFile{Entries: map[string]repo.ChartVersions{
+		"demo": {
+			chartVersion("demo", "2.0.0", false),
+			chartVersion("demo", "1.0.0", true),
+			chartVersion("demo", "0.9.0", false),
+		},
+	}}
+
+	jobs := buildChartVersionJobs(indexData, 10)
+	if len(jobs) != 1 {
+		t.Fatalf("expected deprecated version to stop the scan, got %d jobs: %+v", len(jobs), jobs)
+	}
+	if jobs[0].Version != "2.0.0" {
+		t.Fatalf("expected only the pre-deprecation version 2.0.0, got %q", jobs[0].Version)
+	}
+}
+
+func TestParseWorkspaceMap(t *testing.T) {
+	got := parseWorkspaceMap("cluster-a=ws-a,cluster-b=ws-b")
+	want := map[string]string{"cluster-a": "ws-a", "cluster-b": "ws-b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseWorkspaceMap() = %v, want %v", got, want)
+	}
+}
+
+func TestParseWorkspaceMapEmpty(t *testing.T) {
+	if got := parseWorkspaceMap(""); len(got) != 0 {
+		t.Fatalf("expected empty spec to produce an empty map, got %v", got)
+	}
+}
+
+func TestParseWorkspaceMapSkipsMalformedPairs(t *testing.T) {
+	got := parseWorkspaceMap("cluster-a=ws-a,malformed,cluster-b=ws-b")
+	want := map[string]string{"cluster-a": "ws-a", "cluster-b": "ws-b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseWorkspaceMap() = %v, want %v", got, want)
+	}
+}
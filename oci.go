@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/phuslu/log"
+	"golang.org/x/time/rate"
+	"helm.sh/helm/v3/pkg/registry"
+	"k8s.io/client-go/dynamic"
+)
+
+// uploadOCIChart pulls up to spec.Limit tags of the chart referenced by spec.RepoURL
+// (an "oci://" URL) and uploads each one to target, through the same
+// worker-pool/checkpoint/rate-limit/verification pipeline uploadIndexChart uses for a
+// plain Helm index -- only the per-job fetch (registry pull vs. HTTP GET) differs.
+func uploadOCIChart(target *ClusterTarget, auth *RepoAuth, controlClient dynamic.Interface, checkpoint *Checkpoint, spec ImportSpec) ([]AppRepositoryChartStatus, error) {
+	ref := strings.TrimPrefix(spec.RepoURL, "oci://")
+
+	regClient, err := registry.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCI registry client: %w", err)
+	}
+
+	if auth != nil && auth.Username != "" {
+		host := strings.SplitN(ref, "/", 2)[0]
+		if err := regClient.Login(host, registry.LoginOptBasicAuth(auth.Username, auth.Password)); err != nil {
+			return nil, fmt.Errorf("failed to authenticate with OCI registry %s: %w", host, err)
+		}
+	}
+
+	tags, err := regClient.Tags(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %w", ref, err)
+	}
+
+	chartName := path.Base(ref)
+
+	var keyringPath string
+	if verifyCharts {
+		kr, err := resolveKeyring(controlClient)
+		if err != nil {
+			return nil, err
+		}
+		defer kr.Cleanup()
+		keyringPath = kr.path
+	}
+
+	var jobs []chartVersionJob
+	for taken, tag := range tags {
+		if taken >= spec.Limit {
+			break
+		}
+		jobs = append(jobs, chartVersionJob{Chart: chartName, Version: tag, URL: fmt.Sprintf("%s:%s", ref, tag)})
+	}
+
+	limiter := rate.NewLimiter(rate.Limit(rateLimit), 1)
+	jobResults := runWorkerPool(target.Name, jobs, checkpoint, limiter, func(job chartVersionJob) error {
+		return uploadOCIChartVersion(target, regClient, keyringPath, spec.Mark, job)
+	})
+
+	return summarizeChartResults(jobResults), nil
+}
+
+// uploadOCIChartVersion pulls a single OCI chart ref and posts it to target, the OCI
+// counterpart to uploadChartVersion.
+func uploadOCIChartVersion(target *ClusterTarget, regClient *registry.Client, keyringPath, mark string, job chartVersionJob) error {
+	pullResult, err := regClient.Pull(job.URL, registry.PullOptWithChart(true), registry.PullOptWithProv(verifyCharts))
+	if err != nil {
+		return fmt.Errorf("failed to pull OCI chart %s: %w", job.URL, err)
+	}
+	chartData := pullResult.Chart.Data
+
+	fingerprint, digest, err := verifyChartProvenance(keyringPath, func() ([]byte, error) {
+		if pullResult.Prov == nil {
+			return nil, fmt.Errorf("no provenance layer in OCI artifact %s", job.URL)
+		}
+		return pullResult.Prov.Data, nil
+	}, chartData, job.Chart, job.Version)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/kapis/application.kubesphere.io/v2/apps", target.ServerURL)
+	var response struct {
+		AppName string `json:"appName"`
+	}
+	req := target.RestyClient.R().SetBody(AppRequest{
+		RepoName:     "upload",
+		Package:      base64.StdEncoding.EncodeToString(chartData),
+		CategoryName: mark,
+		Workspace:    target.Workspace,
+		AppType:      "helm",
+	}).SetResult(&response)
+
+	resp, err := req.Post(url)
+	if err != nil {
+		return fmt.Errorf("failed to post app version %s:%s: %w", job.Chart, job.Version, err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("failed to post app %s:%s, status code: %d", job.Chart, job.Version, resp.StatusCode())
+	}
+
+	log.Info().Msgf("[%s] App %s:%s posted successfully", target.Name, job.Chart, job.Version)
+
+	if verifyCharts {
+		ann := map[string]string{
+			"application.kubesphere.io/provenance-fingerprint": fingerprint,
+			"application.kubesphere.io/provenance-digest":      digest,
+		}
+		if err := annotateApp(target.DynamicClient, response.AppName, ann); err != nil {
+			log.Error().Msgf("[%s] Failed to annotate app %s with provenance info: %v", target.Name, response.AppName, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/phuslu/log"
+	"helm.sh/helm/v3/pkg/downloader"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	verifyCharts  bool
+	keyringPath   string
+	keyringSecret string
+)
+
+// verifyChartProvenance fetches a chart's .prov file via fetchProv and checks it
+// against keyringPath (resolved once per run by resolveKeyring, not per chart). It
+// returns the signing key fingerprint and content digest to record as annotations,
+// or an error if the chart is unsigned or the signature doesn't check out against
+// the keyring. A no-op when --verify isn't set.
+func verifyChartProvenance(keyringPath string, fetchProv func() ([]byte, error), chartData []byte, chartName, version string) (fingerprint, digest string, err error) {
+	if !verifyCharts {
+		return "", "", nil
+	}
+
+	provData, err := fetchProv()
+	if err != nil {
+		return "", "", fmt.Errorf("chart %s:%s has no usable provenance file: %w", chartName, version, err)
+	}
+
+	dir, err := os.MkdirTemp("", "app-tool-verify-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp dir for chart verification: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	chartPath := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", chartName, version))
+	if err := os.WriteFile(chartPath, chartData, 0o600); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(chartPath+".prov", provData, 0o600); err != nil {
+		return "", "", err
+	}
+
+	verification, err := downloader.VerifyChart(chartPath, keyringPath)
+	if err != nil {
+		return "", "", fmt.Errorf("chart %s:%s failed provenance verification: %w", chartName, version, err)
+	}
+
+	fingerprint = hex.EncodeToString(verification.SignedBy.PrimaryKey.Fingerprint[:])
+	digest = verification.FileHash
+	log.Info().Msgf("Chart %s:%s verified, signed by %s", chartName, version, fingerprint)
+	return fingerprint, digest, nil
+}
+
+// resolvedKeyring is the local path to a binary OpenPGP keyring to verify against, plus
+// the temp directory backing it (if any) that Cleanup removes once the run is done.
+type resolvedKeyring struct {
+	path string
+	dir  string
+}
+
+// Cleanup removes the temp directory backing kr.path, if one was created. Safe to
+// call on a nil *resolvedKeyring.
+func (kr *resolvedKeyring) Cleanup() {
+	if kr == nil || kr.dir == "" {
+		return
+	}
+	if err := os.RemoveAll(kr.dir); err != nil {
+		log.Warn().Msgf("Failed to remove temp keyring dir %s: %v", kr.dir, err)
+	}
+}
+
+// resolveKeyring resolves the binary OpenPGP keyring to verify against once per run,
+// loading it from --keyring-secret when running in-cluster, or using --keyring
+// directly otherwise.
+func resolveKeyring(controlClient dynamic.Interface) (*resolvedKeyring, error) {
+	if keyringSecret != "" {
+		return loadKeyringFromSecret(controlClient)
+	}
+	if keyringPath == "" {
+		return nil, fmt.Errorf("--verify requires --keyring or --keyring-secret")
+	}
+	return &resolvedKeyring{path: keyringPath}, nil
+}
+
+func loadKeyringFromSecret(client dynamic.Interface) (*resolvedKeyring, error) {
+	secret, err := client.Resource(secretGVR).Namespace(repoAuthNamespace).Get(context.TODO(), keyringSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keyring secret %s/%s: %w", repoAuthNamespace, keyringSecret, err)
+	}
+
+	data, _, err := unstructured.NestedMap(secret.Object, "data")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data from secret %s/%s: %w", repoAuthNamespace, keyringSecret, err)
+	}
+
+	keyring := decodeSecretField(data, "keyring")
+	if keyring == "" {
+		return nil, fmt.Errorf("secret %s/%s has no %q data key", repoAuthNamespace, keyringSecret, "keyring")
+	}
+
+	dir, err := os.MkdirTemp("", "app-tool-keyring-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir for keyring: %w", err)
+	}
+	path := filepath.Join(dir, "keyring.gpg")
+	if err := os.WriteFile(path, []byte(keyring), 0o600); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &resolvedKeyring{path: path, dir: dir}, nil
+}
+
+// annotateApp patches app's annotations with ann on client, retrying on conflict.
+func annotateApp(client dynamic.Interface, name string, ann map[string]string) error {
+	return retryUpdate(client, appGVR, name, annotationMutator(ann), false)
+}
+
+// annotationMutator returns a mutateFunc that merges ann into the object's existing
+// annotations.
+func annotationMutator(ann map[string]string) mutateFunc {
+	return func(obj *unstructured.Unstructured) error {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for k, v := range ann {
+			annotations[k] = v
+		}
+		obj.SetAnnotations(annotations)
+		return nil
+	}
+}
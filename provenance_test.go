@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerifyChartProvenanceNoOpWhenVerifyDisabled(t *testing.T) {
+	origVerify := verifyCharts
+	defer func() { verifyCharts = origVerify }()
+	verifyCharts = false
+
+	called := false
+	fingerprint, digest, err := verifyChartProvenance("/does/not/matter", func() ([]byte, error) {
+		called = true
+		return nil, nil
+	}, []byte("chart data"), "demo", "1.0.0")
+
+	if err != nil {
+		t.Fatalf("expected no-op when --verify is disabled, got error: %v", err)
+	}
+	if fingerprint != "" || digest != "" {
+		t.Fatalf("expected empty fingerprint/digest when --verify is disabled, got %q/%q", fingerprint, digest)
+	}
+	if called {
+		t.Fatalf("expected fetchProv not to be called when --verify is disabled")
+	}
+}
+
+func TestVerifyChartProvenancePropagatesFetchError(t *testing.T) {
+	origVerify := verifyCharts
+	defer func() { verifyCharts = origVerify }()
+	verifyCharts = true
+
+	wantErr := errors.New("no .prov file")
+	_, _, err := verifyChartProvenance("/does/not/matter", func() ([]byte, error) {
+		return nil, wantErr
+	}, []byte("chart data"), "demo", "1.0.0")
+
+	if err == nil || !errors.Is(err, wantErr) {
+		t.Fatalf("expected fetchProv error to be wrapped and propagated, got %v", err)
+	}
+}
+
+func TestResolveKeyringRequiresKeyringOrSecret(t *testing.T) {
+	origPath, origSecret := keyringPath, keyringSecret
+	defer func() { keyringPath, keyringSecret = origPath, origSecret }()
+	keyringPath, keyringSecret = "", ""
+
+	if _, err := resolveKeyring(nil); err == nil {
+		t.Fatalf("expected resolveKeyring to require --keyring or --keyring-secret")
+	}
+}
+
+func TestResolveKeyringUsesKeyringPathDirectly(t *testing.T) {
+	origPath, origSecret := keyringPath, keyringSecret
+	defer func() { keyringPath, keyringSecret = origPath, origSecret }()
+	keyringPath, keyringSecret = "/etc/app-tool/keyring.gpg", ""
+
+	kr, err := resolveKeyring(nil)
+	if err != nil {
+		t.Fatalf("resolveKeyring returned error: %v", err)
+	}
+	if kr.path != keyringPath {
+		t.Fatalf("expected resolveKeyring to use --keyring directly, got path %q", kr.path)
+	}
+	if kr.dir != "" {
+		t.Fatalf("expected no temp dir to be created for a direct --keyring path, got %q", kr.dir)
+	}
+
+	// Cleanup must be a safe no-op since there's no temp dir to remove.
+	kr.Cleanup()
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/phuslu/log"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var secretGVR = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+
+var (
+	repoAuthSecret    string
+	repoAuthNamespace string
+)
+
+// RepoAuth holds the credentials read from --repo-auth-secret, wired into both the
+// Helm getter options and the resty client used to fetch chart tarballs.
+type RepoAuth struct {
+	Username string
+	Password string
+	Bearer   string
+	CertFile string
+	KeyFile  string
+	CAFile   string
+
+	// dir is the temp directory backing CertFile/KeyFile/CAFile, if any. Callers
+	// must Cleanup() once they're done using the cert material.
+	dir string
+}
+
+// Cleanup removes the temp directory backing auth's cert/key/CA material, if one
+// was created. Safe to call on a nil auth or one with no cert material.
+func (auth *RepoAuth) Cleanup() {
+	if auth == nil || auth.dir == "" {
+		return
+	}
+	if err := os.RemoveAll(auth.dir); err != nil {
+		log.Warn().Msgf("Failed to remove temp repo auth dir %s: %v", auth.dir, err)
+	}
+}
+
+// loadRepoAuth reads basic-auth, bearer-token, or client-cert/CA credentials from the
+// Secret named secretName in namespace, read through client. It returns nil, nil when
+// secretName is empty.
+func loadRepoAuth(ctx context.Context, client dynamic.Interface, secretName, namespace string) (*RepoAuth, error) {
+	if secretName == "" {
+		return nil, nil
+	}
+
+	secret, err := client.Resource(secretGVR).Namespace(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repo auth secret %s/%s: %w", namespace, secretName, err)
+	}
+
+	data, _, err := unstructured.NestedMap(secret.Object, "data")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data from secret %s/%s: %w", namespace, secretName, err)
+	}
+
+	auth := &RepoAuth{
+		Username: decodeSecretField(data, "username"),
+		Password: decodeSecretField(data, "password"),
+		Bearer:   decodeSecretField(data, "token"),
+	}
+
+	cert := decodeSecretField(data, "tls.crt")
+	key := decodeSecretField(data, "tls.key")
+	ca := decodeSecretField(data, "ca.crt")
+	if cert != "" || key != "" || ca != "" {
+		dir, err := os.MkdirTemp("", "app-tool-repo-auth-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp dir for repo auth material: %w", err)
+		}
+		auth.dir = dir
+		if cert != "" {
+			auth.CertFile = filepath.Join(dir, "tls.crt")
+			if err := os.WriteFile(auth.CertFile, []byte(cert), 0o600); err != nil {
+				return nil, err
+			}
+		}
+		if key != "" {
+			auth.KeyFile = filepath.Join(dir, "tls.key")
+			if err := os.WriteFile(auth.KeyFile, []byte(key), 0o600); err != nil {
+				return nil, err
+			}
+		}
+		if ca != "" {
+			auth.CAFile = filepath.Join(dir, "ca.crt")
+			if err := os.WriteFile(auth.CAFile, []byte(ca), 0o600); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	log.Info().Msgf("Loaded repo auth credentials from secret %s/%s", namespace, secretName)
+	return auth, nil
+}
+
+// repoEntryFromHelmConfig looks up url in the user's Helm repositories.yaml
+// (settings.RepositoryConfig, taken from HELM_REPOSITORY_CONFIG or Helm's default path),
+// returning a repo.Entry seeded with any credentials saved by `helm repo add
+// --username/--password/...` so repos already configured via the Helm CLI work
+// without duplicating credentials in --repo-auth-secret. Returns a bare Entry for url
+// if the repositories file can't be read or has no matching entry.
+func repoEntryFromHelmConfig(settings *cli.EnvSettings, url string) *repo.Entry {
+	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
+	if err != nil {
+		return &repo.Entry{URL: url}
+	}
+	for _, e := range repoFile.Repositories {
+		if e.URL == url {
+			return e
+		}
+	}
+	return &repo.Entry{URL: url}
+}
+
+// applyRepoAuthToEntry wires auth into a Helm repo.Entry so both index downloads and
+// chart fetches performed by the Helm getters are authenticated.
+func applyRepoAuthToEntry(entry *repo.Entry, auth *RepoAuth) {
+	if auth == nil {
+		return
+	}
+	entry.Username = auth.Username
+	entry.Password = auth.Password
+	entry.CertFile = auth.CertFile
+	entry.KeyFile = auth.KeyFile
+	entry.CAFile = auth.CAFile
+}
+
+// applyRepoAuthToRequest authenticates a single resty request used to fetch a chart
+// tarball directly, independent of the Helm getters.
+func applyRepoAuthToRequest(req *resty.Request, auth *RepoAuth) {
+	if auth == nil {
+		return
+	}
+	if auth.Bearer != "" {
+		req.SetAuthToken(auth.Bearer)
+	} else if auth.Username != "" {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
+
+// applyRepoAuthToClient wires client-cert/CA material into resty_client. TLS config in
+// resty is set per-client rather than per-request, so this must run before any chart
+// download requests are issued against it.
+func applyRepoAuthToClient(resty_client *resty.Client, auth *RepoAuth) error {
+	if auth == nil || (auth.CertFile == "" && auth.CAFile == "") {
+		return nil
+	}
+	if auth.CertFile != "" && auth.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(auth.CertFile, auth.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		resty_client.SetCertificates(cert)
+	}
+	if auth.CAFile != "" {
+		resty_client.SetRootCertificate(auth.CAFile)
+	}
+	return nil
+}
+
+// decodeSecretField returns the base64-decoded value of key in a Secret's "data" map,
+// or "" if the key is absent or not decodable.
+func decodeSecretField(data map[string]interface{}, key string) string {
+	raw, ok := data[key].(string)
+	if !ok || raw == "" {
+		return ""
+	}
+	decoded, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		log.Warn().Msgf("Failed to decode secret field %s: %v", key, err)
+		return ""
+	}
+	return string(decoded)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// updateBackoff is an etcd3-style exponential backoff: retry a handful of times with
+// growing delays rather than hammering the API server on every conflict.
+var updateBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+// mutateFunc mutates obj in place to the desired state. It must be idempotent: calling
+// it again against the object it already produced should be a no-op.
+type mutateFunc func(obj *unstructured.Unstructured) error
+
+// retryUpdate re-fetches the object from client on every conflict and re-applies mutate
+// against the fresh ResourceVersion, up to updateBackoff's step count. If mutate leaves
+// the object unchanged from what's already stored, no write is issued.
+func retryUpdate(client dynamic.Interface, gvr schema.GroupVersionResource, name string, mutate mutateFunc, statusSubresource bool) error {
+	return retry.RetryOnConflict(updateBackoff, func() error {
+		current, err := client.Resource(gvr).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		desired := current.DeepCopy()
+		if err := mutate(desired); err != nil {
+			return err
+		}
+
+		if reflect.DeepEqual(current.Object, desired.Object) {
+			return nil
+		}
+
+		if statusSubresource {
+			_, err = client.Resource(gvr).UpdateStatus(context.TODO(), desired, metav1.UpdateOptions{})
+		} else {
+			_, err = client.Resource(gvr).Update(context.TODO(), desired, metav1.UpdateOptions{})
+		}
+		return err
+	})
+}
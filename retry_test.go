@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var testAppGVR = schema.GroupVersionResource{Group: "application.kubesphere.io", Version: "v2", Resource: "applications"}
+
+func newTestApp(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "application.kubesphere.io/v2",
+		"kind":       "Application",
+		"metadata": map[string]interface{}{
+			"name": name,
+		},
+	}}
+}
+
+func newTestAppClient(objects ...runtime.Object) *fake.FakeDynamicClient {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{testAppGVR: "ApplicationList"}
+	return fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds, objects...)
+}
+
+func TestRetryUpdateAppliesMutation(t *testing.T) {
+	client := newTestAppClient(newTestApp("demo"))
+
+	err := retryUpdate(client, testAppGVR, "demo", func(obj *unstructured.Unstructured) error {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels["application.kubesphere.io/app-store"] = "true"
+		obj.SetLabels(labels)
+		return nil
+	}, false)
+	if err != nil {
+		t.Fatalf("retryUpdate returned error: %v", err)
+	}
+
+	updated, err := client.Resource(testAppGVR).Get(context.TODO(), "demo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get updated object: %v", err)
+	}
+	if updated.GetLabels()["application.kubesphere.io/app-store"] != "true" {
+		t.Fatalf("expected mutate to be applied, got labels %v", updated.GetLabels())
+	}
+}
+
+// TestRetryUpdateNoOpSkipsWrite verifies a mutate that leaves the object unchanged
+// short-circuits instead of issuing an Update call -- this is what lets mutateFuncs
+// be safely re-run on every retry without perturbing state they've already applied.
+func TestRetryUpdateNoOpSkipsWrite(t *testing.T) {
+	client := newTestAppClient(newTestApp("demo"))
+
+	before, err := client.Resource(testAppGVR).Get(context.TODO(), "demo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+
+	err = retryUpdate(client, testAppGVR, "demo", func(obj *unstructured.Unstructured) error {
+		return nil
+	}, false)
+	if err != nil {
+		t.Fatalf("retryUpdate returned error: %v", err)
+	}
+
+	after, err := client.Resource(testAppGVR).Get(context.TODO(), "demo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	if before.GetResourceVersion() != after.GetResourceVersion() {
+		t.Fatalf("expected no-op mutate to skip the write, but resourceVersion changed from %q to %q", before.GetResourceVersion(), after.GetResourceVersion())
+	}
+}
+
+func TestRetryUpdatePropagatesMutateError(t *testing.T) {
+	client := newTestAppClient(newTestApp("demo"))
+
+	wantErr := errors.New("mutate failed")
+	err := retryUpdate(client, testAppGVR, "demo", func(obj *unstructured.Unstructured) error {
+		return wantErr
+	}, false)
+	if err != wantErr {
+		t.Fatalf("expected mutate error %v to propagate, got %v", wantErr, err)
+	}
+}
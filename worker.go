@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	concurrency int
+	rateLimit   float64
+	failFast    bool
+)
+
+// chartVersionJob is one chart version to upload, already filtered for deprecation
+// and the per-chart --limit cap.
+type chartVersionJob struct {
+	Chart   string
+	Version string
+	URL     string
+}
+
+// chartVersionResult is the outcome of uploading a single chartVersionJob.
+type chartVersionResult struct {
+	Chart   string
+	Version string
+	Skipped bool
+	Err     error
+}
+
+// runWorkerPool fans jobs out across `concurrency` workers, each rate-limited by
+// limiter before calling upload, and collects one result per job. Already-checked-off
+// jobs (per checkpoint) are skipped without consuming a rate-limit token. If failFast
+// is set, the pool stops dispatching new jobs as soon as one upload fails.
+func runWorkerPool(cluster string, jobs []chartVersionJob, checkpoint *Checkpoint, limiter *rate.Limiter, upload func(job chartVersionJob) error) []chartVersionResult {
+	results := make([]chartVersionResult, len(jobs))
+
+	jobCh := make(chan int)
+	var aborted bool
+	var abortedMu sync.Mutex
+
+	var wg sync.WaitGroup
+	workers := concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobCh {
+				job := jobs[idx]
+
+				if checkpoint.IsDone(cluster, job.Chart, job.Version) {
+					results[idx] = chartVersionResult{Chart: job.Chart, Version: job.Version, Skipped: true}
+					continue
+				}
+
+				if err := limiter.Wait(context.TODO()); err != nil {
+					results[idx] = chartVersionResult{Chart: job.Chart, Version: job.Version, Err: err}
+					continue
+				}
+
+				err := upload(job)
+				if err == nil {
+					if markErr := checkpoint.MarkDone(cluster, job.Chart, job.Version); markErr != nil {
+						err = fmt.Errorf("upload succeeded but failed to persist checkpoint: %w", markErr)
+					}
+				}
+				results[idx] = chartVersionResult{Chart: job.Chart, Version: job.Version, Err: err}
+				if err != nil && failFast {
+					abortedMu.Lock()
+					aborted = true
+					abortedMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for idx := range jobs {
+		abortedMu.Lock()
+		stop := aborted
+		abortedMu.Unlock()
+		if stop {
+			break
+		}
+		jobCh <- idx
+	}
+	close(jobCh)
+	wg.Wait()
+
+	return results
+}
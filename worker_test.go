@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func newUnlimitedCheckpoint(t *testing.T, done map[string]bool) *Checkpoint {
+	t.Helper()
+	c := &Checkpoint{done: map[string]bool{}, file: filepath.Join(t.TempDir(), "checkpoint.json")}
+	for k, v := range done {
+		c.done[k] = v
+	}
+	return c
+}
+
+func TestRunWorkerPoolSkipsCheckpointedJobs(t *testing.T) {
+	origConcurrency := concurrency
+	defer func() { concurrency = origConcurrency }()
+	concurrency = 1
+
+	checkpoint := newUnlimitedCheckpoint(t, map[string]bool{checkpointKey("cluster", "chart", "1.0.0"): true})
+	jobs := []chartVersionJob{
+		{Chart: "chart", Version: "1.0.0"},
+		{Chart: "chart", Version: "2.0.0"},
+	}
+
+	var mu sync.Mutex
+	var uploaded []string
+	limiter := rate.NewLimiter(rate.Inf, 1)
+
+	results := runWorkerPool("cluster", jobs, checkpoint, limiter, func(job chartVersionJob) error {
+		mu.Lock()
+		uploaded = append(uploaded, job.Version)
+		mu.Unlock()
+		return nil
+	})
+
+	if !results[0].Skipped {
+		t.Fatalf("expected already-checkpointed job %v to be skipped, got %+v", jobs[0], results[0])
+	}
+	if results[1].Skipped {
+		t.Fatalf("expected un-checkpointed job %v to run, got %+v", jobs[1], results[1])
+	}
+	if len(uploaded) != 1 || uploaded[0] != "2.0.0" {
+		t.Fatalf("expected upload to be called only for version 2.0.0, got %v", uploaded)
+	}
+	if !checkpoint.IsDone("cluster", "chart", "2.0.0") {
+		t.Fatalf("expected successful upload to be marked done in the checkpoint")
+	}
+}
+
+func TestRunWorkerPoolFailFastStopsDispatchingNewJobs(t *testing.T) {
+	origConcurrency, origFailFast := concurrency, failFast
+	defer func() { concurrency, failFast = origConcurrency, origFailFast }()
+	concurrency = 1
+	failFast = true
+
+	checkpoint := newUnlimitedCheckpoint(t, nil)
+	jobs := []chartVersionJob{
+		{Chart: "chart", Version: "1.0.0"},
+		{Chart: "chart", Version: "2.0.0"},
+		{Chart: "chart", Version: "3.0.0"},
+	}
+	limiter := rate.NewLimiter(rate.Inf, 1)
+
+	var mu sync.Mutex
+	var calls int
+	runWorkerPool("cluster", jobs, checkpoint, limiter, func(job chartVersionJob) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return errors.New("upload failed")
+	})
+
+	if calls >= len(jobs) {
+		t.Fatalf("expected --fail-fast to stop dispatching before all %d jobs ran, got %d calls", len(jobs), calls)
+	}
+}
+
+func TestRunWorkerPoolSurfacesMarkDoneError(t *testing.T) {
+	origConcurrency := concurrency
+	defer func() { concurrency = origConcurrency }()
+	concurrency = 1
+
+	// A checkpoint pointed at an unwritable file surfaces the persistence failure
+	// through the job's result instead of it being silently dropped.
+	checkpoint := &Checkpoint{done: map[string]bool{}, file: filepath.Join(t.TempDir(), "missing-dir", "checkpoint.json")}
+	jobs := []chartVersionJob{{Chart: "chart", Version: "1.0.0"}}
+	limiter := rate.NewLimiter(rate.Inf, 1)
+
+	results := runWorkerPool("cluster", jobs, checkpoint, limiter, func(job chartVersionJob) error {
+		return nil
+	})
+
+	if results[0].Err == nil {
+		t.Fatalf("expected a checkpoint persistence failure to surface as the job's error")
+	}
+}